@@ -0,0 +1,523 @@
+// This file is part of aph, a tool for generating Argon2id hashes on the
+// command line.
+// Copyright (C) 2020 Jordan Ocokoljic.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package aph
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+var (
+	// ErrorInvalidEncoding is returned by VerifyPassword when the encoded
+	// string is not a valid PHC/Modular Crypt Format string.
+	ErrorInvalidEncoding = errors.New("aph: encoded hash was not a valid PHC string")
+
+	// ErrorUnsupportedAlgorithm is returned when the algorithm identifier in
+	// an encoded hash, or passed to GenerateHashWithAlgorithm, is not one
+	// aph knows how to handle.
+	ErrorUnsupportedAlgorithm = errors.New("aph: algorithm is not supported")
+
+	// ErrorVersionMismatch is returned when the version parameter encoded in
+	// a hash does not match the version aph was built against.
+	ErrorVersionMismatch = errors.New("aph: version of the encoded hash does not match")
+
+	// ErrorMismatchedHashAndPassword is returned by VerifyPassword when the
+	// password does not derive to the hash it was checked against.
+	ErrorMismatchedHashAndPassword = errors.New("aph: password does not match hash")
+
+	// ErrorMemoryTooSmall is returned when the memory budget passed to
+	// GenerateHashWithAlgorithm is too small for the chosen algorithm's
+	// minimum cost, as distinct from ErrorUnsupportedAlgorithm, which
+	// means the algorithm itself was not recognised.
+	ErrorMemoryTooSmall = errors.New("aph: memory budget is too small for this algorithm")
+
+	// ErrorSaltNotSupported is returned by GenerateHashWithSaltAndAlgorithm
+	// when the chosen algorithm always generates its own salt and so
+	// cannot honour a caller supplied one.
+	ErrorSaltNotSupported = errors.New("aph: algorithm does not support an explicit salt")
+)
+
+// Algorithm identifies the password hashing scheme used to produce or
+// verify a ResultSet.Hash.
+type Algorithm int
+
+const (
+	// Argon2id is the default algorithm used throughout the rest of this
+	// package.
+	Argon2id Algorithm = iota
+
+	// Argon2i is a variant of Argon2 that is data-independent, trading some
+	// resistance to GPU cracking for resistance to side-channel attacks.
+	Argon2i
+
+	// Argon2d is a variant of Argon2 that maximises resistance to GPU
+	// cracking attacks at the cost of being vulnerable to side-channel
+	// attacks.
+	Argon2d
+
+	// Bcrypt is the long standing blowfish based password hashing scheme.
+	Bcrypt
+
+	// Scrypt is a memory-hard key derivation function, here used as a
+	// password hashing scheme.
+	Scrypt
+)
+
+// String returns the PHC identifier that aph uses for this algorithm.
+func (a Algorithm) String() string {
+	switch a {
+	case Argon2id:
+		return "argon2id"
+	case Argon2i:
+		return "argon2i"
+	case Argon2d:
+		return "argon2d"
+	case Bcrypt:
+		return "bcrypt"
+	case Scrypt:
+		return "scrypt"
+	default:
+		return "unknown"
+	}
+}
+
+// scryptR is scrypt's block size parameter. aph fixes it at the commonly
+// recommended value of 8 and derives the cost parameter N and the
+// parallelism parameter p from the caller's memory and thread budget
+// instead, so that ResultSet reports the parameters that were actually
+// used.
+const scryptR = 8
+
+// VerifyPassword parses an encoded PHC/Modular Crypt Format string, as
+// found in ResultSet.Hash, and reports whether password derives to it. It
+// rejects malformed strings, unsupported algorithm identifiers, and
+// version mismatches with the typed errors declared in this file.
+func VerifyPassword(encoded string, password string) (bool, error) {
+	if len(encoded) == 0 || encoded[0] != '$' {
+		return false, ErrorInvalidEncoding
+	}
+
+	parts := strings.Split(encoded[1:], "$")
+	if len(parts) == 0 {
+		return false, ErrorInvalidEncoding
+	}
+
+	switch parts[0] {
+	case "argon2id", "argon2i":
+		return verifyArgon2(parts, password)
+	case "2a", "2b", "2y":
+		return verifyBcrypt(encoded, password)
+	case "scrypt":
+		return verifyScrypt(parts, password)
+	default:
+		return false, ErrorUnsupportedAlgorithm
+	}
+}
+
+// verifyArgon2 verifies the argon2i and argon2id PHC strings, which share
+// the same field layout and only differ in the key derivation function
+// used.
+func verifyArgon2(parts []string, password string) (bool, error) {
+	if len(parts) != 5 {
+		return false, ErrorInvalidEncoding
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[1], "v=%d", &version); err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	if version != argon2.Version {
+		return false, ErrorVersionMismatch
+	}
+
+	var memory, timeCost uint32
+	var threads uint8
+	_, err := fmt.Sscanf(
+		parts[2],
+		"m=%d,t=%d,p=%d",
+		&memory,
+		&timeCost,
+		&threads,
+	)
+
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	if timeCost < 1 || threads < 1 || len(hash) == 0 {
+		return false, ErrorInvalidEncoding
+	}
+
+	var derived []byte
+	length := uint32(len(hash))
+	if parts[0] == "argon2id" {
+		derived = argon2.IDKey([]byte(password), salt, timeCost, memory, threads, length)
+	} else {
+		derived = argon2.Key([]byte(password), salt, timeCost, memory, threads, length)
+	}
+
+	if subtle.ConstantTimeCompare(derived, hash) != 1 {
+		return false, ErrorMismatchedHashAndPassword
+	}
+
+	return true, nil
+}
+
+// verifyBcrypt verifies a bcrypt hash. Bcrypt hashes carry their own
+// encoding rather than the argon2 style PHC string, so the whole encoded
+// value is handed to the bcrypt package as-is.
+func verifyBcrypt(encoded string, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, bcrypt.ErrMismatchedHashAndPassword):
+		return false, ErrorMismatchedHashAndPassword
+	default:
+		return false, ErrorInvalidEncoding
+	}
+}
+
+// verifyScrypt verifies an aph encoded scrypt hash, in the form
+// $scrypt$ln=15,r=8,p=1$<salt>$<hash>.
+func verifyScrypt(parts []string, password string) (bool, error) {
+	if len(parts) != 4 {
+		return false, ErrorInvalidEncoding
+	}
+
+	var ln, r, p int
+	_, err := fmt.Sscanf(parts[1], "ln=%d,r=%d,p=%d", &ln, &r, &p)
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	if ln < 1 || r < 1 || p < 1 {
+		return false, ErrorInvalidEncoding
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	hash, err := base64.RawStdEncoding.DecodeString(parts[3])
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	derived, err := scrypt.Key([]byte(password), salt, 1<<ln, r, p, len(hash))
+	if err != nil {
+		return false, ErrorInvalidEncoding
+	}
+
+	if subtle.ConstantTimeCompare(derived, hash) != 1 {
+		return false, ErrorMismatchedHashAndPassword
+	}
+
+	return true, nil
+}
+
+// GenerateHashWithAlgorithm generates a hash and ResultSet using the
+// requested Algorithm. Argon2id is handled by the existing generateHash
+// path; the other algorithms are generated directly against their
+// golang.org/x/crypto implementations.
+//
+// Argon2d is not currently supported, as golang.org/x/crypto/argon2 does
+// not expose the data-dependent key derivation function; it is accepted
+// here only so that the error returned is ErrorUnsupportedAlgorithm rather
+// than an unrecognised flag value.
+//
+// Bcrypt has no notion of memory cost or parallelism, so seconds is
+// reused as its cost factor rather than a duration; see generateBcrypt.
+// Scrypt has no notion of a time cost, so seconds is ignored and its cost
+// parameters are instead derived from memory and threads; see
+// deriveScryptParameters.
+func GenerateHashWithAlgorithm(
+	algorithm Algorithm,
+	seconds int,
+	threads int,
+	memory int,
+	length int,
+	key string,
+) (ResultSet, error) {
+	switch algorithm {
+	case Argon2id:
+		return GenerateHash(seconds, threads, memory, length, key)
+	case Argon2i:
+		return generateArgon2i(seconds, threads, memory, length, key)
+	case Bcrypt:
+		return generateBcrypt(seconds, key)
+	case Scrypt:
+		return generateScrypt(threads, memory, length, key)
+	default:
+		return ResultSet{}, ErrorUnsupportedAlgorithm
+	}
+}
+
+// generateArgon2i generates a hash using the argon2i variant and encodes
+// it the same way the argon2id wrapper does.
+func generateArgon2i(
+	seconds int,
+	threads int,
+	memory int,
+	length int,
+	key string,
+) (ResultSet, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return ResultSet{}, err
+	}
+
+	start := time.Now()
+	hash := argon2.Key(
+		[]byte(key),
+		salt,
+		uint32(seconds),
+		uint32(memory),
+		uint8(threads),
+		uint32(length),
+	)
+	duration := time.Since(start)
+
+	encoded := fmt.Sprintf(
+		"$argon2i$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		memory,
+		seconds,
+		threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+
+	return ResultSet{
+		Time:       seconds,
+		Threads:    threads,
+		Memory:     memory,
+		Length:     length,
+		Key:        key,
+		Hash:       encoded,
+		Characters: len(encoded),
+		Duration:   duration,
+		Salt:       string(salt),
+		Algorithm:  Argon2i,
+	}, nil
+}
+
+// generateArgon2iWithSalt generates a hash using the argon2i variant and
+// the caller supplied salt, rather than a randomly generated one.
+func generateArgon2iWithSalt(
+	seconds int,
+	threads int,
+	memory int,
+	length int,
+	key string,
+	salt string,
+) (ResultSet, error) {
+	saltBytes := []byte(salt)
+
+	start := time.Now()
+	hash := argon2.Key(
+		[]byte(key),
+		saltBytes,
+		uint32(seconds),
+		uint32(memory),
+		uint8(threads),
+		uint32(length),
+	)
+	duration := time.Since(start)
+
+	encoded := fmt.Sprintf(
+		"$argon2i$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		memory,
+		seconds,
+		threads,
+		base64.RawStdEncoding.EncodeToString(saltBytes),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+
+	return ResultSet{
+		Time:       seconds,
+		Threads:    threads,
+		Memory:     memory,
+		Length:     length,
+		Key:        key,
+		Hash:       encoded,
+		Characters: len(encoded),
+		Duration:   duration,
+		Salt:       salt,
+		Algorithm:  Argon2i,
+	}, nil
+}
+
+// GenerateHashWithSaltAndAlgorithm generates a hash and ResultSet using the
+// requested Algorithm and a caller supplied salt, for reproducible output
+// such as test fixtures. Only Argon2id and Argon2i accept an explicit
+// salt; Bcrypt and Scrypt always generate their own internally, so
+// ErrorSaltNotSupported is returned for them rather than the salt being
+// silently ignored.
+func GenerateHashWithSaltAndAlgorithm(
+	algorithm Algorithm,
+	seconds int,
+	threads int,
+	memory int,
+	length int,
+	key string,
+	salt string,
+) (ResultSet, error) {
+	switch algorithm {
+	case Argon2id:
+		return GenerateHashWithSalt(seconds, threads, memory, length, key, salt)
+	case Argon2i:
+		return generateArgon2iWithSalt(seconds, threads, memory, length, key, salt)
+	case Bcrypt, Scrypt:
+		return ResultSet{}, ErrorSaltNotSupported
+	default:
+		return ResultSet{}, ErrorUnsupportedAlgorithm
+	}
+}
+
+// generateBcrypt generates a bcrypt hash. Bcrypt has no notion of memory
+// or time cost in milliseconds; it takes a cost factor directly, in the
+// range [bcrypt.MinCost, bcrypt.MaxCost]. GenerateHashWithAlgorithm reuses
+// the seconds parameter (the result of ParseTime, in milliseconds) to
+// carry this value, so a caller selecting Bcrypt must pass a stamp such
+// as "10ms" to request a cost of 10, rather than a duration they expect
+// hashing to take.
+func generateBcrypt(cost int, key string) (ResultSet, error) {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		return ResultSet{}, fmt.Errorf(
+			"aph: bcrypt cost must be between %d and %d, got %d",
+			bcrypt.MinCost,
+			bcrypt.MaxCost,
+			cost,
+		)
+	}
+
+	start := time.Now()
+	hash, err := bcrypt.GenerateFromPassword([]byte(key), cost)
+	duration := time.Since(start)
+	if err != nil {
+		return ResultSet{}, err
+	}
+
+	return ResultSet{
+		Time:       cost,
+		Threads:    1,
+		Length:     len(hash),
+		Key:        key,
+		Hash:       string(hash),
+		Characters: len(hash),
+		Duration:   duration,
+		Algorithm:  Bcrypt,
+	}, nil
+}
+
+// deriveScryptParameters picks scrypt's cost parameter N, returned as its
+// base-2 logarithm ln, from memoryKB and threads, so that the memory
+// scrypt is actually asked to use matches what the caller requested.
+// scrypt's working set is approximately 128*r*p*N bytes, so with r and p
+// fixed, ln is the largest value for which that product still fits
+// memoryKB. It returns ErrorMemoryTooSmall if memoryKB is too small to fit
+// even the smallest valid N (2) with the requested thread count.
+func deriveScryptParameters(memoryKB int, threads int) (ln int, r int, p int, err error) {
+	r = scryptR
+	p = threads
+	if p < 1 {
+		p = 1
+	}
+
+	memoryBytes := int64(memoryKB) * 1024
+	perDoubling := int64(128 * r * p)
+
+	if perDoubling*2 > memoryBytes {
+		return 0, 0, 0, ErrorMemoryTooSmall
+	}
+
+	for ln = 1; perDoubling<<uint(ln+1) <= memoryBytes; ln++ {
+	}
+
+	return ln, r, p, nil
+}
+
+// generateScrypt generates a scrypt hash and encodes it using aph's
+// $scrypt$ln=...,r=...,p=...$salt$hash convention. Its cost parameters
+// are derived from memory and threads by deriveScryptParameters, rather
+// than fixed, so that ResultSet.Memory and ResultSet.Threads reflect the
+// cost that was actually used.
+func generateScrypt(threads int, memory int, length int, key string) (ResultSet, error) {
+	ln, r, p, err := deriveScryptParameters(memory, threads)
+	if err != nil {
+		return ResultSet{}, err
+	}
+
+	n := 1 << uint(ln)
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return ResultSet{}, err
+	}
+
+	start := time.Now()
+	hash, err := scrypt.Key([]byte(key), salt, n, r, p, length)
+	duration := time.Since(start)
+	if err != nil {
+		return ResultSet{}, err
+	}
+
+	encoded := fmt.Sprintf(
+		"$scrypt$ln=%d,r=%d,p=%d$%s$%s",
+		ln,
+		r,
+		p,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+
+	return ResultSet{
+		Threads:    p,
+		Memory:     (128 * r * n * p) / 1024,
+		Length:     length,
+		Key:        key,
+		Hash:       encoded,
+		Characters: len(encoded),
+		Duration:   duration,
+		Salt:       string(salt),
+		Algorithm:  Scrypt,
+	}, nil
+}
@@ -18,35 +18,11 @@
 package aph
 
 import (
-	"errors"
-	"math"
-	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/JordanOcokoljic/argon2id"
 )
 
-var (
-	// ErrorMalformedStamp is returned by the Parse functions when the stamp
-	// provided that doesn't match the format necessary for parsing.
-	ErrorMalformedStamp = errors.New("aph: provided stamp was malformed")
-
-	// ErrorSplitAtomic is returned by the Parse functions when the stamp
-	// provided has a fractional value for an atomic type (KB or ms).
-	ErrorSplitAtomic = errors.New("aph: cannot use fractional value with type")
-)
-
-var (
-	// parseTime is a regex that can determine if a provided timestamp is valid
-	// and also extracts the necessary deatils out of the stamp.
-	parseTime = regexp.MustCompile("(\\d+(?:\\.\\d+)?)(ms|s)")
-
-	// parseMemory is a regex that can determine if a provided memorystamp is
-	// valid and also extracts the necessary details out of the stamp.
-	parseMemory = regexp.MustCompile("(\\d+(?:\\.\\d+)?)(KB|MB|GB)")
-)
-
 // ResultSet is a collection of information about the generation of a hash, it
 // includes details such as how long generation took and what the overall size
 // of the hash is.
@@ -60,66 +36,7 @@ type ResultSet struct {
 	Characters int
 	Duration   time.Duration
 	Salt       string
-}
-
-// ParseTime will take a string in either Xs or Xms where X is an number. It
-// will return the corresponding number of milliseconds that the time reprsents
-// or an error if the string is malformed.
-func ParseTime(stamp string) (int, error) {
-	details := parseTime.FindStringSubmatch(stamp)
-	if details == nil || len(details) != 3 {
-		return 0, ErrorMalformedStamp
-	}
-
-	t, err := strconv.ParseFloat(details[1], 64)
-	if err != nil {
-		return 0, err
-	}
-
-	var millis int
-	switch details[2] {
-	case "ms":
-		if math.Trunc(t) != t {
-			return 0, ErrorSplitAtomic
-		}
-
-		millis = int(t)
-	case "s":
-		millis = int(t * 1000)
-	}
-
-	return millis, nil
-}
-
-// ParseMemory will take a string in the form of one of XKB, XMB, or XGB where
-// X is a number. It will return the corresponding number of KB that the stamp
-// represents or an error if the string is malformed.
-func ParseMemory(stamp string) (int, error) {
-	details := parseMemory.FindStringSubmatch(stamp)
-	if details == nil || len(details) != 3 {
-		return 0, ErrorMalformedStamp
-	}
-
-	s, err := strconv.ParseFloat(details[1], 64)
-	if err != nil {
-		return 0, err
-	}
-
-	var kilobytes int
-	switch details[2] {
-	case "KB":
-		if math.Trunc(s) != s {
-			return 0, ErrorSplitAtomic
-		}
-
-		kilobytes = int(s)
-	case "MB":
-		kilobytes = int(s * 1024)
-	case "GB":
-		kilobytes = int(s * 1024 * 1024)
-	}
-
-	return kilobytes, nil
+	Algorithm  Algorithm
 }
 
 // generateHash will perform the hashing of the password and the generation of
@@ -129,6 +46,8 @@ func generateHash(
 	params argon2id.Parameters,
 	password []byte,
 ) (ResultSet, error) {
+	defer zero(password)
+
 	var hash []byte
 	var duration time.Duration
 
@@ -150,11 +69,20 @@ func generateHash(
 		Characters: len(hash),
 		Duration:   duration,
 		Salt:       string(params.Salt),
+		Algorithm:  Argon2id,
 	}
 
 	return rs, nil
 }
 
+// zero overwrites every byte of b with the zero value, so that sensitive
+// data such as a password does not linger in memory after use.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
+
 // GenerateHash will generate a hash and store the details and result into a
 // ResultSet and return the set.
 func GenerateHash(
@@ -18,7 +18,9 @@
 package aph_test
 
 import (
+	"encoding/json"
 	"testing"
+	"time"
 
 	"github.com/JordanOcokoljic/aph"
 )
@@ -48,6 +50,30 @@ func TestParseTime(t *testing.T) {
 			millis: 750,
 			err:    nil,
 		},
+		{
+			name:   "Compound",
+			stamp:  "1m30s",
+			millis: 90000,
+			err:    nil,
+		},
+		{
+			name:   "CompoundWithMilliseconds",
+			stamp:  "2s500ms",
+			millis: 2500,
+			err:    nil,
+		},
+		{
+			name:   "Hours",
+			stamp:  "1h",
+			millis: 3600000,
+			err:    nil,
+		},
+		{
+			name:   "WhitespaceTolerance",
+			stamp:  "  1s  ",
+			millis: 1000,
+			err:    nil,
+		},
 		{
 			name:   "MalformedStamp",
 			stamp:  "32",
@@ -60,6 +86,18 @@ func TestParseTime(t *testing.T) {
 			millis: 0,
 			err:    aph.ErrorSplitAtomic,
 		},
+		{
+			name:   "Negative",
+			stamp:  "-1s",
+			millis: 0,
+			err:    aph.ErrorOutOfRange,
+		},
+		{
+			name:   "Overflow",
+			stamp:  "2000000h",
+			millis: 0,
+			err:    aph.ErrorOutOfRange,
+		},
 	}
 
 	for _, test := range tests {
@@ -73,8 +111,8 @@ func TestParseTime(t *testing.T) {
 				s.Errorf("expected %d but was %d", test.millis, millis)
 			}
 
-			if test.err != nil && err == nil {
-				s.Errorf("expected an error to occur but none did")
+			if test.err != nil && err != test.err {
+				s.Errorf("expected error %v but was %v", test.err, err)
 			}
 		})
 	}
@@ -84,6 +122,7 @@ func TestParseMemory(t *testing.T) {
 	tests := []struct {
 		name      string
 		stamp     string
+		si        bool
 		kilobytes int
 		err       error
 	}{
@@ -104,12 +143,50 @@ func TestParseMemory(t *testing.T) {
 			stamp:     "1GB",
 			kilobytes: 1048576,
 		},
+		{
+			name:      "IECKibibytes",
+			stamp:     "500KiB",
+			kilobytes: 500,
+			err:       nil,
+		},
+		{
+			name:      "IECMebibytes",
+			stamp:     "10MiB",
+			kilobytes: 10240,
+			err:       nil,
+		},
+		{
+			name:      "IECUnaffectedBySI",
+			stamp:     "10MiB",
+			si:        true,
+			kilobytes: 10240,
+			err:       nil,
+		},
+		{
+			name:      "SIKilobytes",
+			stamp:     "1024kB",
+			kilobytes: 1000,
+			err:       nil,
+		},
+		{
+			name:      "SIMegabytesWithFlag",
+			stamp:     "1.024MB",
+			si:        true,
+			kilobytes: 1000,
+			err:       nil,
+		},
 		{
 			name:      "MalformedStamp",
 			stamp:     "10B",
 			kilobytes: 0,
 			err:       aph.ErrorMalformedStamp,
 		},
+		{
+			name:      "WhitespaceTolerance",
+			stamp:     " 500KB ",
+			kilobytes: 500,
+			err:       nil,
+		},
 		{
 			name:      "PartialAtomicType",
 			stamp:     "0.5KB",
@@ -128,11 +205,23 @@ func TestParseMemory(t *testing.T) {
 			kilobytes: 786432,
 			err:       nil,
 		},
+		{
+			name:      "Negative",
+			stamp:     "-1KB",
+			kilobytes: 0,
+			err:       aph.ErrorOutOfRange,
+		},
+		{
+			name:      "Overflow",
+			stamp:     "5000000GB",
+			kilobytes: 0,
+			err:       aph.ErrorOutOfRange,
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(s *testing.T) {
-			kilobytes, err := aph.ParseMemory(test.stamp)
+			kilobytes, err := aph.ParseMemory(test.stamp, test.si)
 			if err != nil && test.err == nil {
 				s.Fatalf(err.Error())
 			}
@@ -141,8 +230,8 @@ func TestParseMemory(t *testing.T) {
 				s.Errorf("expected %d but was %d", test.kilobytes, kilobytes)
 			}
 
-			if test.err != nil && err == nil {
-				s.Errorf("expected an error to occur but none did")
+			if test.err != nil && err != test.err {
+				s.Errorf("expected error %v but was %v", test.err, err)
 			}
 		})
 	}
@@ -240,3 +329,250 @@ func TestGenerateHashWithSalt(t *testing.T) {
 		)
 	}
 }
+
+func TestVerifyPassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded string
+		key     string
+		matches bool
+		err     error
+	}{
+		{
+			name:    "MatchingPassword",
+			encoded: "$argon2id$v=19$m=65536,t=1,p=1$bXlzYWx0$siUWf7GXJ34",
+			key:     "mypassword",
+			matches: true,
+			err:     nil,
+		},
+		{
+			name:    "MismatchedPassword",
+			encoded: "$argon2id$v=19$m=65536,t=1,p=1$bXlzYWx0$siUWf7GXJ34",
+			key:     "wrongpassword",
+			matches: false,
+			err:     aph.ErrorMismatchedHashAndPassword,
+		},
+		{
+			name:    "UnsupportedAlgorithm",
+			encoded: "$md5$bXlzYWx0$siUWf7GXJ34",
+			key:     "mypassword",
+			matches: false,
+			err:     aph.ErrorUnsupportedAlgorithm,
+		},
+		{
+			name:    "MalformedEncoding",
+			encoded: "not-a-phc-string",
+			key:     "mypassword",
+			matches: false,
+			err:     aph.ErrorInvalidEncoding,
+		},
+		{
+			name:    "VersionMismatch",
+			encoded: "$argon2id$v=1$m=65536,t=1,p=1$bXlzYWx0$siUWf7GXJ34",
+			key:     "mypassword",
+			matches: false,
+			err:     aph.ErrorVersionMismatch,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(s *testing.T) {
+			matches, err := aph.VerifyPassword(test.encoded, test.key)
+			if matches != test.matches {
+				s.Errorf("expected matches to be %t but was %t", test.matches, matches)
+			}
+
+			if test.err != err {
+				s.Errorf("expected error %v but was %v", test.err, err)
+			}
+		})
+	}
+}
+
+func TestResultSetMarshalJSON(t *testing.T) {
+	tests := []struct {
+		name       string
+		rs         aph.ResultSet
+		algorithm  string
+		hasVersion bool
+	}{
+		{
+			name: "Argon2id",
+			rs: aph.ResultSet{
+				Time:      1,
+				Threads:   1,
+				Memory:    65536,
+				Length:    8,
+				Hash:      "$argon2id$v=19$m=65536,t=1,p=1$bXlzYWx0$siUWf7GXJ34",
+				Salt:      "mysalt",
+				Algorithm: aph.Argon2id,
+			},
+			algorithm:  "argon2id",
+			hasVersion: true,
+		},
+		{
+			name: "Bcrypt",
+			rs: aph.ResultSet{
+				Time:      10,
+				Threads:   1,
+				Hash:      "$2a$10$eImiTXuWVxfM37uY4JANjQ==",
+				Algorithm: aph.Bcrypt,
+			},
+			algorithm:  "bcrypt",
+			hasVersion: false,
+		},
+		{
+			name: "Scrypt",
+			rs: aph.ResultSet{
+				Threads:   1,
+				Memory:    16384,
+				Hash:      "$scrypt$ln=14,r=8,p=1$bXlzYWx0$aGFzaA",
+				Algorithm: aph.Scrypt,
+			},
+			algorithm:  "scrypt",
+			hasVersion: false,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(s *testing.T) {
+			encoded, err := json.Marshal(test.rs)
+			if err != nil {
+				s.Fatalf(err.Error())
+			}
+
+			var decoded map[string]interface{}
+			if err := json.Unmarshal(encoded, &decoded); err != nil {
+				s.Fatalf(err.Error())
+			}
+
+			if decoded["algorithm"] != test.algorithm {
+				s.Errorf(
+					"expected algorithm to be %s but was %v",
+					test.algorithm,
+					decoded["algorithm"],
+				)
+			}
+
+			if decoded["hash"] != test.rs.Hash {
+				s.Errorf("expected hash to be %s but was %v", test.rs.Hash, decoded["hash"])
+			}
+
+			_, hasVersion := decoded["version"]
+			if hasVersion != test.hasVersion {
+				s.Errorf(
+					"expected version to be present: %t but was %t",
+					test.hasVersion,
+					hasVersion,
+				)
+			}
+		})
+	}
+}
+
+func TestCalibrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		target      time.Duration
+		maxMemoryKB int
+		threads     int
+		length      int
+		err         error
+	}{
+		{
+			name:        "FindsParameters",
+			target:      50 * time.Millisecond,
+			maxMemoryKB: 64 * 1024,
+			threads:     1,
+			length:      8,
+			err:         nil,
+		},
+		{
+			name:        "TargetTooSmall",
+			target:      time.Nanosecond,
+			maxMemoryKB: 64 * 1024,
+			threads:     1,
+			length:      8,
+			err:         aph.ErrorCalibrationFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(s *testing.T) {
+			params, err := aph.Calibrate(
+				test.target,
+				test.maxMemoryKB,
+				test.threads,
+				test.length,
+			)
+
+			if test.err != nil {
+				if err != test.err {
+					s.Errorf("expected error %v but was %v", test.err, err)
+				}
+
+				return
+			}
+
+			if err != nil {
+				s.Fatalf(err.Error())
+			}
+
+			if params.Time == 0 {
+				s.Errorf("expected a non-zero time cost")
+			}
+
+			if int(params.Memory) != test.maxMemoryKB {
+				s.Errorf(
+					"expected memory to be %d but was %d",
+					test.maxMemoryKB,
+					params.Memory,
+				)
+			}
+
+			if int(params.Threads) != test.threads {
+				s.Errorf(
+					"expected threads to be %d but was %d",
+					test.threads,
+					params.Threads,
+				)
+			}
+
+			if int(params.Length) != test.length {
+				s.Errorf(
+					"expected length to be %d but was %d",
+					test.length,
+					params.Length,
+				)
+			}
+
+			measured, err := aph.GenerateHash(
+				int(params.Time),
+				int(params.Threads),
+				int(params.Memory),
+				int(params.Length),
+				"calibration-precision-check",
+			)
+
+			if err != nil {
+				s.Fatalf(err.Error())
+			}
+
+			// Calibrate targets +/-10%; a single extra measurement here
+			// adds its own timing noise on top of that, so this allows a
+			// wider +/-25% band rather than re-asserting the exact figure.
+			tolerance := test.target / 4
+			lowerBound := test.target - tolerance
+			upperBound := test.target + tolerance
+
+			if measured.Duration < lowerBound || measured.Duration > upperBound {
+				s.Errorf(
+					"expected hashing with the returned parameters to take "+
+						"near %s, but took %s",
+					test.target,
+					measured.Duration,
+				)
+			}
+		})
+	}
+}
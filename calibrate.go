@@ -0,0 +1,173 @@
+// This file is part of aph, a tool for generating Argon2id hashes on the
+// command line.
+// Copyright (C) 2020 Jordan Ocokoljic.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package aph
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/JordanOcokoljic/argon2id"
+)
+
+// ErrorCalibrationFailed is returned by Calibrate when no set of parameters
+// could be found that both fits within maxMemoryKB and completes in at
+// most target.
+var ErrorCalibrationFailed = errors.New(
+	"aph: could not find parameters that fit within the memory and time budget",
+)
+
+// calibrationIterations is the number of candidates Calibrate will try
+// before giving up, to guarantee that it terminates.
+const calibrationIterations = 20
+
+// calibrationPassword is hashed repeatedly while searching for parameters;
+// its value is irrelevant, as only the time taken to hash it is measured.
+const calibrationPassword = "aph-calibration-password"
+
+// Calibrate searches for Argon2id parameters that hash a password within
+// 10% of target, without exceeding maxMemoryKB. It pins memory to
+// maxMemoryKB and threads to threads, then doubles the time cost starting
+// from 1 until a sample lands within the tolerance window or overshoots
+// it; once time costs above and below the window are known, it bisects
+// between them to converge on one inside it. Across both phases it
+// measures at most calibrationIterations samples, to guarantee that it
+// terminates; if the window is never reached, it returns the largest
+// time cost that was measured to complete in at most target.
+func Calibrate(
+	target time.Duration,
+	maxMemoryKB int,
+	threads int,
+	length int,
+) (argon2id.Parameters, error) {
+	lowerBound := target - target/10
+	upperBound := target + target/10
+
+	sample := func(timeCost uint32) (argon2id.Parameters, time.Duration, error) {
+		params, err := argon2id.NewParameters(
+			timeCost,
+			uint32(maxMemoryKB),
+			uint8(threads),
+			uint32(length),
+		)
+
+		if err != nil {
+			return argon2id.Parameters{}, 0, err
+		}
+
+		duration, err := calibrationSample(params)
+		return params, duration, err
+	}
+
+	var (
+		best     argon2id.Parameters
+		haveBest bool
+		low      uint32 = 1
+		high     uint32
+	)
+
+	remaining := calibrationIterations
+	for remaining > 0 {
+		remaining--
+
+		params, duration, err := sample(low)
+		if err != nil {
+			return argon2id.Parameters{}, err
+		}
+
+		if duration >= lowerBound && duration <= upperBound {
+			return params, nil
+		}
+
+		if duration <= target {
+			best, haveBest = params, true
+		}
+
+		if duration > upperBound {
+			high = low
+			break
+		}
+
+		low *= 2
+	}
+
+	// A high of 0 means doubling never overshot upperBound within the
+	// iteration budget; there is nothing left to bisect between.
+	if high != 0 {
+		low := uint32(1)
+		if haveBest {
+			low = best.Time
+		}
+
+		for remaining > 0 && low+1 < high {
+			remaining--
+			mid := low + (high-low)/2
+
+			params, duration, err := sample(mid)
+			if err != nil {
+				return argon2id.Parameters{}, err
+			}
+
+			if duration >= lowerBound && duration <= upperBound {
+				return params, nil
+			}
+
+			if duration <= target {
+				best, haveBest = params, true
+				low = mid
+			} else {
+				high = mid
+			}
+		}
+	}
+
+	if !haveBest {
+		return argon2id.Parameters{}, ErrorCalibrationFailed
+	}
+
+	return best, nil
+}
+
+// calibrationSample hashes calibrationPassword with params, discarding the
+// first run as warmup, and returns the median of three further samples.
+func calibrationSample(params argon2id.Parameters) (time.Duration, error) {
+	if _, err := argon2id.GenerateFromPassword(
+		[]byte(calibrationPassword),
+		params,
+	); err != nil {
+		return 0, err
+	}
+
+	samples := make([]time.Duration, 3)
+	for i := range samples {
+		start := time.Now()
+		_, err := argon2id.GenerateFromPassword(
+			[]byte(calibrationPassword),
+			params,
+		)
+
+		if err != nil {
+			return 0, err
+		}
+
+		samples[i] = time.Since(start)
+	}
+
+	sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+	return samples[1], nil
+}
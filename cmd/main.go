@@ -18,12 +18,19 @@
 package main
 
 import (
+	"bufio"
 	"encoding/base64"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/JordanOcokoljic/aph"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 // printAndExit checks if the error is non-nil and if it is prints the error
@@ -35,9 +42,172 @@ func printAndExit(err error) {
 	}
 }
 
+// parseAlgorithm converts the value of the --algorithm flag into an
+// aph.Algorithm, exiting the application if the name is not recognised.
+func parseAlgorithm(name string) aph.Algorithm {
+	switch name {
+	case "argon2id":
+		return aph.Argon2id
+	case "argon2i":
+		return aph.Argon2i
+	case "argon2d":
+		return aph.Argon2d
+	case "bcrypt":
+		return aph.Bcrypt
+	case "scrypt":
+		return aph.Scrypt
+	default:
+		fmt.Printf("aph: unknown algorithm %q\n", name)
+		os.Exit(1)
+		return aph.Argon2id
+	}
+}
+
+// resolveKey determines the password to hash. If prompt is true, it is
+// read from the controlling terminal without echoing; otherwise a key of
+// "-" is read from stdin, a key prefixed with "@" is read from the named
+// file, and any other key is used as-is. Passing secrets directly on the
+// command line leaks them into the process list and shell history, so
+// callers should prefer one of the other forms where possible.
+func resolveKey(key string, prompt bool) (string, error) {
+	if prompt {
+		fmt.Fprint(os.Stderr, "Password: ")
+		password, err := terminal.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return "", err
+		}
+
+		return string(password), nil
+	}
+
+	switch {
+	case key == "-":
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return "", err
+		}
+
+		return strings.TrimRight(line, "\r\n"), nil
+	case strings.HasPrefix(key, "@"):
+		contents, err := os.ReadFile(key[1:])
+		if err != nil {
+			return "", err
+		}
+
+		return strings.TrimRight(string(contents), "\r\n"), nil
+	default:
+		return key, nil
+	}
+}
+
 func main() {
 	args := os.Args[1:]
-	if len(args) < 5 {
+	if len(args) > 0 && args[0] == "calibrate" {
+		runCalibrate(args[1:])
+		return
+	}
+
+	runGenerate(args)
+}
+
+// runCalibrate implements the `aph calibrate` subcommand, printing the
+// Argon2id parameters found for the requested duration and memory budget.
+func runCalibrate(args []string) {
+	fs := flag.NewFlagSet("aph calibrate", flag.ExitOnError)
+	si := fs.Bool(
+		"si",
+		false,
+		"interpret ambiguous memory units (KB, MB, GB) as 1000-based rather than 1024-based",
+	)
+
+	fs.Parse(args)
+	args = fs.Args()
+
+	if len(args) < 4 {
+		fmt.Println("aph: not enough arguments provided")
+		os.Exit(1)
+	}
+
+	millis, err := aph.ParseTime(args[0])
+	printAndExit(err)
+
+	memory, err := aph.ParseMemory(args[1], *si)
+	printAndExit(err)
+
+	threads, err := strconv.Atoi(args[2])
+	printAndExit(err)
+
+	length, err := strconv.Atoi(args[3])
+	printAndExit(err)
+
+	params, err := aph.Calibrate(
+		time.Duration(millis)*time.Millisecond,
+		memory,
+		threads,
+		length,
+	)
+
+	printAndExit(err)
+
+	fmt.Printf(
+		`Calibration Results:
+Time: %d
+Memory: %dKB
+Threads: %d
+Length: %d
+`,
+		params.Time,
+		params.Memory,
+		params.Threads,
+		params.Length,
+	)
+}
+
+// runGenerate implements the default `aph` invocation, generating a hash
+// from the provided parameters and key.
+func runGenerate(args []string) {
+	fs := flag.NewFlagSet("aph", flag.ExitOnError)
+	algorithm := fs.String(
+		"algorithm",
+		"argon2id",
+		"the algorithm to hash with: argon2id, argon2i, argon2d, bcrypt or "+
+			"scrypt (bcrypt and scrypt cannot be combined with an explicit salt)",
+	)
+
+	prompt := fs.Bool(
+		"prompt",
+		false,
+		"read the password from the controlling terminal instead of the key argument",
+	)
+
+	format := fs.String(
+		"format",
+		"human",
+		"output format: human, json or phc",
+	)
+
+	quiet := fs.Bool(
+		"quiet",
+		false,
+		`suppress the "Generation Results" banner in human output`,
+	)
+
+	si := fs.Bool(
+		"si",
+		false,
+		"interpret ambiguous memory units (KB, MB, GB) as 1000-based rather than 1024-based",
+	)
+
+	fs.Parse(args)
+	args = fs.Args()
+
+	minArgs := 5
+	if *prompt {
+		minArgs = 4
+	}
+
+	if len(args) < minArgs {
 		fmt.Println("aph: not enough arguments provided")
 		os.Exit(1)
 	}
@@ -59,22 +229,28 @@ func main() {
 	threads, err = strconv.Atoi(args[1])
 	printAndExit(err)
 
-	memory, err = aph.ParseMemory(args[2])
+	memory, err = aph.ParseMemory(args[2], *si)
 	printAndExit(err)
 
 	length, err = strconv.Atoi(args[3])
 	printAndExit(err)
 
-	key = args[4]
+	var keyArg string
+	saltIndex := 5
+	if *prompt {
+		saltIndex = 4
+	} else {
+		keyArg = args[4]
+	}
 
-	switch len(args) {
-	case 5:
-		result, err = aph.GenerateHash(seconds, threads, memory, length, key)
-		printAndExit(err)
-		prettySalt = base64.RawStdEncoding.EncodeToString([]byte(result.Salt))
-	case 6:
-		salt = args[5]
-		result, err = aph.GenerateHashWithSalt(
+	key, err = resolveKey(keyArg, *prompt)
+	printAndExit(err)
+
+	switch {
+	case len(args) > saltIndex:
+		salt = args[saltIndex]
+		result, err = aph.GenerateHashWithSaltAndAlgorithm(
+			parseAlgorithm(*algorithm),
 			seconds,
 			threads,
 			memory,
@@ -85,11 +261,43 @@ func main() {
 
 		printAndExit(err)
 		prettySalt = result.Salt
+	default:
+		result, err = aph.GenerateHashWithAlgorithm(
+			parseAlgorithm(*algorithm),
+			seconds,
+			threads,
+			memory,
+			length,
+			key,
+		)
+
+		printAndExit(err)
+		prettySalt = base64.RawStdEncoding.EncodeToString([]byte(result.Salt))
 	}
 
-	fmt.Printf(
-		`Generation Results:
-Time: %dms
+	printResult(result, prettySalt, *format, *quiet)
+}
+
+// printResult writes result to stdout in the requested format. The phc
+// format emits only the encoded hash, for use in shell substitution; json
+// emits result.MarshalJSON(); human prints the existing banner, which
+// quiet suppresses.
+func printResult(result aph.ResultSet, prettySalt string, format string, quiet bool) {
+	switch format {
+	case "phc":
+		fmt.Println(result.Hash)
+	case "json":
+		encoded, err := json.Marshal(result)
+		printAndExit(err)
+		fmt.Println(string(encoded))
+	default:
+		banner := "Generation Results:\n"
+		if quiet {
+			banner = ""
+		}
+
+		fmt.Printf(
+			banner+`Time: %dms
 Threads: %d
 Memory: %dKB
 Length: %d
@@ -101,14 +309,15 @@ Hash: %s
 Hash Length: %d
 Generation Time: %dms
 `,
-		result.Time,
-		result.Threads,
-		result.Memory,
-		result.Length,
-		result.Key,
-		prettySalt,
-		result.Hash,
-		result.Characters,
-		result.Duration.Milliseconds(),
-	)
+			result.Time,
+			result.Threads,
+			result.Memory,
+			result.Length,
+			result.Key,
+			prettySalt,
+			result.Hash,
+			result.Characters,
+			result.Duration.Milliseconds(),
+		)
+	}
 }
@@ -0,0 +1,123 @@
+// This file is part of aph, a tool for generating Argon2id hashes on the
+// command line.
+// Copyright (C) 2020 Jordan Ocokoljic.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// replaceStdin points os.Stdin at a pipe pre-filled with contents, and
+// returns a function that restores the original os.Stdin.
+func replaceStdin(t *testing.T, contents string) func() {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if _, err := w.WriteString(contents); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	w.Close()
+
+	original := os.Stdin
+	os.Stdin = r
+
+	return func() {
+		os.Stdin = original
+		r.Close()
+	}
+}
+
+func TestResolveKeyStdin(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "TrimsTrailingNewline",
+			input:    "mypassword\n",
+			expected: "mypassword",
+		},
+		{
+			name:     "TrimsTrailingCRLF",
+			input:    "mypassword\r\n",
+			expected: "mypassword",
+		},
+		{
+			name:     "NoTrailingNewline",
+			input:    "mypassword",
+			expected: "mypassword",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(s *testing.T) {
+			defer replaceStdin(s, test.input)()
+
+			key, err := resolveKey("-", false)
+			if err != nil {
+				s.Fatalf(err.Error())
+			}
+
+			if key != test.expected {
+				s.Errorf("expected key to be %q but was %q", test.expected, key)
+			}
+		})
+	}
+}
+
+func TestResolveKeyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "key.txt")
+	if err := os.WriteFile(path, []byte("mypassword\n"), 0600); err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	key, err := resolveKey("@"+path, false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if key != "mypassword" {
+		t.Errorf("expected key to be %q but was %q", "mypassword", key)
+	}
+}
+
+func TestResolveKeyFileNotFound(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.txt")
+	_, err := resolveKey("@"+path, false)
+	if err == nil {
+		t.Errorf("expected an error but got none")
+	}
+}
+
+func TestResolveKeyLiteral(t *testing.T) {
+	key, err := resolveKey("mypassword", false)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	if key != "mypassword" {
+		t.Errorf("expected key to be %q but was %q", "mypassword", key)
+	}
+}
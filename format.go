@@ -0,0 +1,61 @@
+// This file is part of aph, a tool for generating Argon2id hashes on the
+// command line.
+// Copyright (C) 2020 Jordan Ocokoljic.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package aph
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// MarshalJSON implements json.Marshaler for ResultSet, emitting stable
+// field names so downstream tools can pipe aph's output without parsing
+// the human readable banner.
+//
+// version is only meaningful for the argon2 family of algorithms; it is
+// omitted for Bcrypt and Scrypt hashes, which have no such concept.
+func (rs ResultSet) MarshalJSON() ([]byte, error) {
+	var version int
+	switch rs.Algorithm {
+	case Argon2id, Argon2i, Argon2d:
+		version = argon2.Version
+	}
+
+	return json.Marshal(struct {
+		TimeMS     int    `json:"time_ms"`
+		Threads    int    `json:"threads"`
+		MemoryKB   int    `json:"memory_kb"`
+		Length     int    `json:"length"`
+		SaltB64    string `json:"salt_b64"`
+		Hash       string `json:"hash"`
+		DurationMS int64  `json:"duration_ms"`
+		Algorithm  string `json:"algorithm"`
+		Version    int    `json:"version,omitempty"`
+	}{
+		TimeMS:     rs.Time,
+		Threads:    rs.Threads,
+		MemoryKB:   rs.Memory,
+		Length:     rs.Length,
+		SaltB64:    base64.RawStdEncoding.EncodeToString([]byte(rs.Salt)),
+		Hash:       rs.Hash,
+		DurationMS: rs.Duration.Milliseconds(),
+		Algorithm:  rs.Algorithm.String(),
+		Version:    version,
+	})
+}
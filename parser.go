@@ -0,0 +1,149 @@
+// This file is part of aph, a tool for generating Argon2id hashes on the
+// command line.
+// Copyright (C) 2020 Jordan Ocokoljic.
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <https://www.gnu.org/licenses/>.
+
+package aph
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrorMalformedStamp is returned by the Parse functions when the stamp
+	// provided that doesn't match the format necessary for parsing.
+	ErrorMalformedStamp = errors.New("aph: provided stamp was malformed")
+
+	// ErrorSplitAtomic is returned by the Parse functions when the stamp
+	// provided has a fractional value that does not represent a whole
+	// number of the atomic type the stamp is converted to (KB or ms).
+	ErrorSplitAtomic = errors.New("aph: cannot use fractional value with type")
+
+	// ErrorOutOfRange is returned by the Parse functions when the stamp
+	// provided is negative, or would overflow Argon2's uint32 parameters.
+	ErrorOutOfRange = errors.New("aph: provided stamp is out of range")
+)
+
+// memoryUnits maps a memory stamp's unit to the number of bytes it
+// represents. KiB, MiB and GiB are always 1024-based, as defined by IEC
+// 80000-13. KB, MB and GB are also 1024-based, for backwards compatibility
+// with versions of aph that predate this table.
+var memoryUnits = map[string]float64{
+	"KiB": 1024,
+	"MiB": 1024 * 1024,
+	"GiB": 1024 * 1024 * 1024,
+	"KB":  1024,
+	"MB":  1024 * 1024,
+	"GB":  1024 * 1024 * 1024,
+	"kB":  1000,
+}
+
+// memoryUnitsSI is used in place of memoryUnits when the caller passes
+// si=true to ParseMemory. It interprets the ambiguous KB, MB and GB units
+// using SI's 1000-based convention instead of the 1024-based default,
+// matching how disk and network vendors advertise capacity. The IEC units
+// are unambiguous and so are unaffected.
+var memoryUnitsSI = map[string]float64{
+	"KiB": 1024,
+	"MiB": 1024 * 1024,
+	"GiB": 1024 * 1024 * 1024,
+	"KB":  1000,
+	"MB":  1000 * 1000,
+	"GB":  1000 * 1000 * 1000,
+	"kB":  1000,
+}
+
+// ParseTime takes a Go duration stamp (for example 500ms, 1s, or the
+// compound 1m30s) and returns the number of milliseconds it represents, or
+// an error if the stamp is malformed. Parsing is delegated to
+// time.ParseDuration, which already tokenizes compound stamps; aph only
+// adds whitespace tolerance and the range/precision checks Argon2's
+// parameters require.
+func ParseTime(stamp string) (int, error) {
+	duration, err := time.ParseDuration(strings.TrimSpace(stamp))
+	if err != nil {
+		return 0, ErrorMalformedStamp
+	}
+
+	if duration%time.Millisecond != 0 {
+		return 0, ErrorSplitAtomic
+	}
+
+	millis := duration.Milliseconds()
+	if millis < 0 || millis > math.MaxUint32 {
+		return 0, ErrorOutOfRange
+	}
+
+	return int(millis), nil
+}
+
+// ParseMemory takes a memory stamp such as 500KB, 10MiB, or 0.5GB and
+// returns the number of kilobytes it represents, or an error if the stamp
+// is malformed. If si is true, the ambiguous KB/MB/GB units are
+// interpreted as 1000-based rather than aph's default of 1024-based; the
+// unambiguous KiB/MiB/GiB units are unaffected.
+func ParseMemory(stamp string, si bool) (int, error) {
+	value, unit, err := tokenizeMemoryStamp(stamp)
+	if err != nil {
+		return 0, err
+	}
+
+	units := memoryUnits
+	if si {
+		units = memoryUnitsSI
+	}
+
+	bytesPerUnit, ok := units[unit]
+	if !ok {
+		return 0, ErrorMalformedStamp
+	}
+
+	kilobytes := (value * bytesPerUnit) / 1024
+	if math.Trunc(kilobytes) != kilobytes {
+		return 0, ErrorSplitAtomic
+	}
+
+	if kilobytes < 0 || kilobytes > math.MaxUint32 {
+		return 0, ErrorOutOfRange
+	}
+
+	return int(kilobytes), nil
+}
+
+// tokenizeMemoryStamp splits a memory stamp into its numeric value and
+// unit, tolerating leading and trailing whitespace.
+func tokenizeMemoryStamp(stamp string) (float64, string, error) {
+	stamp = strings.TrimSpace(stamp)
+
+	i := 0
+	for i < len(stamp) && (stamp[i] == '-' || stamp[i] == '.' || (stamp[i] >= '0' && stamp[i] <= '9')) {
+		i++
+	}
+
+	if i == 0 || i == len(stamp) {
+		return 0, "", ErrorMalformedStamp
+	}
+
+	value, err := strconv.ParseFloat(stamp[:i], 64)
+	if err != nil {
+		return 0, "", ErrorMalformedStamp
+	}
+
+	return value, stamp[i:], nil
+}